@@ -0,0 +1,173 @@
+package netvip
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// AddrEventType identifies whether an AddrEvent reports an address being
+// added or deleted.
+type AddrEventType int
+
+const (
+	AddrAdded AddrEventType = iota
+	AddrDeleted
+)
+
+func (t AddrEventType) String() string {
+	switch t {
+	case AddrAdded:
+		return "Added"
+	case AddrDeleted:
+		return "Deleted"
+	default:
+		return "Unknown"
+	}
+}
+
+// AddrEvent reports an address addition or removal observed by
+// WatchAddrChanges.
+type AddrEvent struct {
+	Type    AddrEventType
+	Prefix  netip.Prefix
+	IfIndex int
+	Label   string
+	Flags   uint32
+}
+
+// WatchAddrChanges watches for IPv4 and IPv6 address changes on any
+// interface and invokes callback for each one observed. Unlike WatchGARP,
+// which only reacts when a peer announces a VIP over ARP, this lets a
+// VRRP-like supervisor react instantly when another node adds or removes
+// the VIP, without polling net.Interface.Addrs().
+func WatchAddrChanges(ctx context.Context, callback func(AddrEvent) error) error {
+	c, err := dialNetlinkRoute(&netlink.Config{
+		Groups: unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR,
+	})
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err := c.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			return err
+		}
+		msgs, err := c.Receive()
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			return err
+		}
+		for _, msg := range msgs {
+			typ := uint16(msg.Header.Type)
+			if typ != unix.RTM_NEWADDR && typ != unix.RTM_DELADDR {
+				continue
+			}
+			ev, err := parseAddrEvent(msg)
+			if err != nil {
+				return err
+			}
+			if err := callback(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// InterfacePrefixes returns the address prefixes (CIDR) currently assigned
+// to intf, dumped via RTM_GETADDR with NLM_F_DUMP instead of parsing the
+// strings returned by intf.Addrs().
+func InterfacePrefixes(intf *net.Interface) ([]netip.Prefix, error) {
+	c, err := dialNetlinkRoute(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	req := netlink.Message{
+		Header: netlink.Header{
+			Type:  netlink.HeaderType(unix.RTM_GETADDR),
+			Flags: netlink.Request | netlink.Dump,
+		},
+		Data: ifAddrmsg{}.serialize(),
+	}
+	msgs, err := c.Execute(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefixes []netip.Prefix
+	for _, msg := range msgs {
+		ev, err := parseAddrEvent(msg)
+		if err != nil {
+			return nil, err
+		}
+		if ev.IfIndex == intf.Index && ev.Prefix.IsValid() {
+			prefixes = append(prefixes, ev.Prefix)
+		}
+	}
+	return prefixes, nil
+}
+
+// parseAddrEvent decodes the ifAddrmsg header of msg and walks its
+// attributes for IFA_ADDRESS, IFA_LOCAL, IFA_LABEL and IFA_FLAGS.
+func parseAddrEvent(msg netlink.Message) (AddrEvent, error) {
+	if len(msg.Data) < ifAddrmsgLen {
+		return AddrEvent{}, errors.New("netvip: short ifaddrmsg")
+	}
+	hdr := parseIfAddrmsg(msg.Data)
+
+	ev := AddrEvent{IfIndex: int(hdr.Index)}
+	if uint16(msg.Header.Type) == unix.RTM_DELADDR {
+		ev.Type = AddrDeleted
+	} else {
+		ev.Type = AddrAdded
+	}
+
+	ad, err := netlink.NewAttributeDecoder(msg.Data[ifAddrmsgLen:])
+	if err != nil {
+		return AddrEvent{}, err
+	}
+
+	var addr, local netip.Addr
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.IFA_ADDRESS:
+			addr, _ = netip.AddrFromSlice(ad.Bytes())
+		case unix.IFA_LOCAL:
+			local, _ = netip.AddrFromSlice(ad.Bytes())
+		case unix.IFA_LABEL:
+			ev.Label = ad.String()
+		case unix.IFA_FLAGS:
+			ev.Flags = ad.Uint32()
+		}
+	}
+	if err := ad.Err(); err != nil {
+		return AddrEvent{}, err
+	}
+
+	// IFA_LOCAL is the address actually configured on the interface;
+	// IFA_ADDRESS is only different for point-to-point links.
+	if local.IsValid() {
+		addr = local
+	}
+	if addr.IsValid() {
+		ev.Prefix = netip.PrefixFrom(addr, int(hdr.Prefixlen))
+	}
+	return ev, nil
+}
@@ -0,0 +1,99 @@
+package netvip
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseAddrEvent(t *testing.T) {
+	hdr := ifAddrmsg{
+		Family:    unix.AF_INET,
+		Prefixlen: 32,
+		Index:     7,
+	}
+	ae := netlink.NewAttributeEncoder()
+	addr := netip.MustParseAddr("192.0.2.10")
+	ae.Bytes(unix.IFA_LOCAL, addr.AsSlice())
+	ae.Bytes(unix.IFA_ADDRESS, addr.AsSlice())
+	ae.String(unix.IFA_LABEL, "eth0:0")
+	ae.Uint32(unix.IFA_FLAGS, IFA_F_NODAD)
+	attrs, err := ae.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := netlink.Message{
+		Header: netlink.Header{Type: netlink.HeaderType(unix.RTM_NEWADDR)},
+		Data:   append(hdr.serialize(), attrs...),
+	}
+
+	ev, err := parseAddrEvent(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ev.Type, AddrAdded; got != want {
+		t.Errorf("Type mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := ev.IfIndex, 7; got != want {
+		t.Errorf("IfIndex mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := ev.Prefix, netip.PrefixFrom(addr, 32); got != want {
+		t.Errorf("Prefix mismatch, got=%s, want=%s", got, want)
+	}
+	if got, want := ev.Label, "eth0:0"; got != want {
+		t.Errorf("Label mismatch, got=%q, want=%q", got, want)
+	}
+	if got, want := ev.Flags, uint32(IFA_F_NODAD); got != want {
+		t.Errorf("Flags mismatch, got=%#x, want=%#x", got, want)
+	}
+}
+
+func TestParseAddrEventDeleted(t *testing.T) {
+	hdr := ifAddrmsg{
+		Family:    unix.AF_INET6,
+		Prefixlen: 64,
+		Index:     3,
+	}
+	ae := netlink.NewAttributeEncoder()
+	addr := netip.MustParseAddr("2001:db8::1")
+	ae.Bytes(unix.IFA_ADDRESS, addr.AsSlice())
+	attrs, err := ae.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := netlink.Message{
+		Header: netlink.Header{Type: netlink.HeaderType(unix.RTM_DELADDR)},
+		Data:   append(hdr.serialize(), attrs...),
+	}
+
+	ev, err := parseAddrEvent(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ev.Type, AddrDeleted; got != want {
+		t.Errorf("Type mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := ev.IfIndex, 3; got != want {
+		t.Errorf("IfIndex mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := ev.Prefix, netip.PrefixFrom(addr, 64); got != want {
+		t.Errorf("Prefix mismatch, got=%s, want=%s", got, want)
+	}
+	if ev.Label != "" {
+		t.Errorf("Label mismatch, got=%q, want empty", ev.Label)
+	}
+}
+
+func TestParseAddrEventShortMessage(t *testing.T) {
+	msg := netlink.Message{
+		Header: netlink.Header{Type: netlink.HeaderType(unix.RTM_NEWADDR)},
+		Data:   []byte{0, 1, 2},
+	}
+	if _, err := parseAddrEvent(msg); err == nil {
+		t.Error("expected an error for a message shorter than ifAddrmsgLen")
+	}
+}
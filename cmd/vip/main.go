@@ -15,6 +15,7 @@ import (
 
 	"github.com/hnakamur/netvip"
 	"github.com/mdlayher/arp"
+	"github.com/mdlayher/ndp"
 	"github.com/urfave/cli/v2"
 )
 
@@ -57,7 +58,7 @@ func main() {
 						Name:    "garp",
 						Aliases: []string{"g"},
 						Value:   true,
-						Usage:   "send GARP (Gratuitous ARP) packet when address is added or even when it is alreay added",
+						Usage:   "send a GARP (Gratuitous ARP) packet for an IPv4 address, or an unsolicited Neighbor Advertisement for an IPv6 address, when address is added or even when it is alreay added",
 					},
 					&cli.BoolFlag{
 						Name:    "quiet",
@@ -178,10 +179,17 @@ func execAddCommand(intf *net.Interface, cidr netip.Prefix, label string, sendsG
 	}
 
 	if sendsGARP {
-		if err := netvip.SendGARP(intf, cidr.Addr()); err != nil {
-			return err
+		if cidr.Addr().Is4() {
+			if err := netvip.SendGARP(intf, cidr.Addr()); err != nil {
+				return err
+			}
+			fmt.Fprintf(appWriter, "sent GARP packet for address %s at interface %s\n", cidr, intf.Name)
+		} else {
+			if err := netvip.SendUnsolicitedNA(intf, cidr.Addr(), nil); err != nil {
+				return err
+			}
+			fmt.Fprintf(appWriter, "sent unsolicited NA packet for address %s at interface %s\n", cidr, intf.Name)
 		}
-		fmt.Fprintf(appWriter, "sent GARP packet for address %s at interface %s\n", cidr, intf.Name)
 	}
 
 	return nil
@@ -189,9 +197,18 @@ func execAddCommand(intf *net.Interface, cidr netip.Prefix, label string, sendsG
 
 func execDelCommand(intf *net.Interface, cidr netip.Prefix, appWriter io.Writer, watch bool) error {
 	if watch {
-		return netvip.WatchGARP(context.TODO(), cidr.Addr(), func(pkt *arp.Packet) error {
-			if bytes.Equal(pkt.SenderHardwareAddr, intf.HardwareAddr) {
-				fmt.Fprintf(appWriter, "interface %s received GARP packet for VIP %s sent from itself.\n", intf.Name, cidr)
+		if cidr.Addr().Is4() {
+			return netvip.WatchGARP(context.TODO(), intf, cidr.Addr(), func(pkt *arp.Packet) error {
+				if bytes.Equal(pkt.SenderHardwareAddr, intf.HardwareAddr) {
+					fmt.Fprintf(appWriter, "interface %s received GARP packet for VIP %s sent from itself.\n", intf.Name, cidr)
+					return nil
+				}
+				return deleteVIP(intf, cidr, appWriter)
+			})
+		}
+		return netvip.WatchUnsolicitedNA(context.TODO(), intf, cidr.Addr(), func(na *ndp.NeighborAdvertisement) error {
+			if naSentFromSelf(na, intf) {
+				fmt.Fprintf(appWriter, "interface %s received NA packet for VIP %s sent from itself.\n", intf.Name, cidr)
 				return nil
 			}
 			return deleteVIP(intf, cidr, appWriter)
@@ -201,6 +218,19 @@ func execDelCommand(intf *net.Interface, cidr netip.Prefix, appWriter io.Writer,
 	return deleteVIP(intf, cidr, appWriter)
 }
 
+// naSentFromSelf reports whether na's Target Link-Layer Address option
+// carries intf's own hardware address, i.e. the advertisement is the one
+// this process itself sent via netvip.SendUnsolicitedNA.
+func naSentFromSelf(na *ndp.NeighborAdvertisement, intf *net.Interface) bool {
+	for _, opt := range na.Options {
+		lla, ok := opt.(*ndp.LinkLayerAddress)
+		if ok && lla.Direction == ndp.Target {
+			return bytes.Equal(lla.Addr, intf.HardwareAddr)
+		}
+	}
+	return false
+}
+
 func deleteVIP(intf *net.Interface, cidr netip.Prefix, appWriter io.Writer) error {
 	if err := netvip.InterfaceDelPrefix(intf, cidr); err != nil {
 		if !errors.Is(err, syscall.EADDRNOTAVAIL) {
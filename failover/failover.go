@@ -0,0 +1,440 @@
+// Package failover implements a VRRP-style cooperative failover subsystem
+// on top of the netvip package's VIP and GARP/NA primitives. Instead of
+// callers manually adding and deleting a VIP, a Group lets a cluster of
+// nodes agree on which one of them owns it at any given time.
+package failover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/hnakamur/netvip"
+	"github.com/mdlayher/arp"
+	"github.com/mdlayher/ndp"
+)
+
+// DefaultAdvertInterval is the default interval between master
+// advertisements, analogous to VRRP's Advertisement_Interval.
+const DefaultAdvertInterval = time.Second
+
+// DefaultPort is the default UDP port Group uses to exchange hello
+// packets between peers.
+const DefaultPort = 5213
+
+// State is the state of a Group's failover state machine.
+type State int
+
+const (
+	// Backup is the initial state, and the state a node returns to when
+	// it observes a higher-priority peer.
+	Backup State = iota
+	// Master is the state of the node currently owning the VIP.
+	Master
+)
+
+func (s State) String() string {
+	switch s {
+	case Master:
+		return "Master"
+	default:
+		return "Backup"
+	}
+}
+
+// Group owns a VIP across a cluster of cooperating nodes. Peers exchange
+// lightweight UDP hello packets carrying priority and the VIP address (so
+// multiple Groups can safely share a Port), and the highest-priority
+// reachable node becomes master, installs the VIP with
+// netvip.ReplaceAddr and announces it with netvip.SendGARP or
+// netvip.SendUnsolicitedNA, and re-announces itself to Peers with a hello
+// every AdvertInterval.
+// Backups track the master's advertisements, a BPF-filtered wire watcher
+// (netvip.WatchGARP for IPv4 VIPs, netvip.WatchUnsolicitedNAFrames for
+// IPv6 ones) and netvip.WatchAddrChanges, and preempt after
+// 3*AdvertInterval plus a priority-based skew, per RFC 3768 ยง6.2's
+// Master_Down_Interval. A hello with Priority equal to this node's own is
+// broken deterministically by comparing addresses, mirroring VRRP's own
+// primary-IP-address tiebreak (RFC 3768 ยง6.1).
+//
+// This is a cooperative protocol, not an interoperable implementation of
+// VRRP or VRRPv3: hello packets are netvip-specific, sent over UDP rather
+// than IP protocol 112, so all members of a Group must be netvip nodes.
+type Group struct {
+	// Interface is the network interface the VIP is announced on.
+	Interface *net.Interface
+	// VIP is the virtual IP address (CIDR) owned by the group.
+	VIP netip.Prefix
+	// Label is an optional label applied to the VIP, e.g. "eth0:0".
+	Label string
+	// Priority ranks this node against its peers; the highest-priority
+	// reachable node becomes master. Valid range is 1-255.
+	Priority uint8
+	// LocalAddr is this node's own address. It is used only to break a
+	// tie when a peer's hello carries a Priority equal to this node's
+	// own, the same way VRRP breaks ties on primary IP address.
+	LocalAddr netip.Addr
+	// Peers lists the addresses of the other nodes participating in the
+	// group.
+	Peers []netip.Addr
+	// Port is the UDP port hello packets are exchanged on. It defaults to
+	// DefaultPort if zero.
+	Port int
+	// AdvertInterval is the interval between master advertisements. It
+	// defaults to DefaultAdvertInterval if zero.
+	AdvertInterval time.Duration
+
+	// OnBecomeMaster is called when this node transitions to Master,
+	// after the VIP has been installed and announced.
+	OnBecomeMaster func()
+	// OnBecomeBackup is called when this node transitions to Backup.
+	OnBecomeBackup func()
+	// OnFault is called with a non-fatal error observed while running,
+	// e.g. a failure to watch for external changes to the VIP. Run
+	// returns only on fatal errors; OnFault is for callers that want to
+	// log or alert on degraded conditions that don't stop the group.
+	OnFault func(error)
+}
+
+type hello struct {
+	priority uint8
+	from     netip.Addr
+}
+
+// replaceAddr, delAddr, sendGARP and sendUnsolicitedNA are package-level
+// seams over the corresponding netvip functions, analogous to netvip's own
+// dialNetlinkRoute seam, so tests can exercise Group.Run's state machine
+// without a real interface or root.
+var (
+	replaceAddr       = netvip.ReplaceAddr
+	delAddr           = netvip.DelAddr
+	sendGARP          = netvip.SendGARP
+	sendUnsolicitedNA = netvip.SendUnsolicitedNA
+)
+
+// Run starts the group's state machine. It blocks until ctx is canceled,
+// in which case it relinquishes the VIP if it was master and returns
+// ctx.Err(), or until an unrecoverable error occurs.
+func (g *Group) Run(ctx context.Context) error {
+	if g.Interface == nil {
+		return errors.New("failover: Group.Interface must not be nil")
+	}
+	if !g.VIP.IsValid() {
+		return errors.New("failover: Group.VIP must be a valid prefix")
+	}
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: g.port()})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	helloCh := make(chan hello)
+	takeoverCh := make(chan struct{}, 1)
+	addrCh := make(chan struct{}, 1)
+	errCh := make(chan error, 3)
+
+	go g.receiveHellos(ctx, conn, helloCh, errCh)
+	go g.watchAddrChanges(ctx, addrCh, errCh)
+	if g.VIP.Addr().Is4() {
+		go g.watchGARP(ctx, takeoverCh, errCh)
+	} else {
+		go g.watchNDP(ctx, takeoverCh, errCh)
+	}
+
+	state := Backup
+	masterTimer := time.NewTimer(g.masterDownInterval())
+	defer masterTimer.Stop()
+	advertTicker := time.NewTicker(g.advertInterval())
+	defer advertTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if state == Master {
+				g.relinquish()
+			}
+			return ctx.Err()
+
+		case err := <-errCh:
+			if g.OnFault != nil {
+				g.OnFault(err)
+			}
+
+		case h := <-helloCh:
+			if !g.shouldYieldTo(h) {
+				continue
+			}
+			if state == Master {
+				state = Backup
+				g.notifyBackup()
+			}
+			resetTimer(masterTimer, g.masterDownInterval())
+
+		case <-takeoverCh:
+			// Another node is announcing the VIP over the wire: it is
+			// alive, so reset our deadline regardless of state.
+			resetTimer(masterTimer, g.masterDownInterval())
+
+		case <-addrCh:
+			// The VIP appeared on or disappeared from our own interface,
+			// e.g. through a conflicting tool, a leftover lease or our own
+			// takeover: treat it the same as a wire-level sighting.
+			resetTimer(masterTimer, g.masterDownInterval())
+
+		case <-advertTicker.C:
+			if state == Master {
+				if err := g.sendHello(conn); err != nil && g.OnFault != nil {
+					g.OnFault(err)
+				}
+			}
+
+		case <-masterTimer.C:
+			if state == Backup {
+				state = Master
+				if err := g.takeover(); err != nil {
+					return err
+				}
+				g.notifyMaster()
+				if err := g.sendHello(conn); err != nil && g.OnFault != nil {
+					g.OnFault(err)
+				}
+			}
+			masterTimer.Reset(g.masterDownInterval())
+		}
+	}
+}
+
+func (g *Group) takeover() error {
+	if err := replaceAddr(g.Interface, g.VIP, g.Label, nil); err != nil {
+		return err
+	}
+	if g.VIP.Addr().Is4() {
+		return sendGARP(g.Interface, g.VIP.Addr())
+	}
+	return sendUnsolicitedNA(g.Interface, g.VIP.Addr(), nil)
+}
+
+// relinquish best-effort removes the VIP when Run returns while this node
+// is master, so a clean shutdown doesn't leave a stale address behind.
+func (g *Group) relinquish() {
+	_ = delAddr(g.Interface, g.VIP)
+}
+
+func (g *Group) notifyMaster() {
+	if g.OnBecomeMaster != nil {
+		g.OnBecomeMaster()
+	}
+}
+
+func (g *Group) notifyBackup() {
+	if g.OnBecomeBackup != nil {
+		g.OnBecomeBackup()
+	}
+}
+
+// maxHelloLen bounds a hello datagram: one priority byte followed by the
+// VIP address, at most 16 bytes for an IPv6 address.
+const maxHelloLen = 1 + 16
+
+// helloPayload encodes g's priority and VIP address into a hello datagram,
+// so peers sharing a Port across different Groups can tell which VIP a
+// hello is for.
+func (g *Group) helloPayload() ([]byte, error) {
+	vipBytes, err := g.VIP.Addr().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{g.Priority}, vipBytes...), nil
+}
+
+func (g *Group) sendHello(conn *net.UDPConn) error {
+	buf, err := g.helloPayload()
+	if err != nil {
+		return err
+	}
+	for _, peer := range g.Peers {
+		addr := net.UDPAddrFromAddrPort(netip.AddrPortFrom(peer, uint16(g.port())))
+		if _, err := conn.WriteToUDP(buf, addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Group) receiveHellos(ctx context.Context, conn *net.UDPConn, out chan<- hello, errCh chan<- error) {
+	buf := make([]byte, maxHelloLen)
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			errCh <- err
+			return
+		}
+		n, addr, err := conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+					continue
+				}
+			}
+			errCh <- err
+			return
+		}
+		if n < 1 || !g.isPeer(addr.Addr()) {
+			continue
+		}
+		var vip netip.Addr
+		if err := vip.UnmarshalBinary(buf[1:n]); err != nil || vip != g.VIP.Addr() {
+			continue
+		}
+		h := hello{priority: buf[0], from: addr.Addr()}
+		select {
+		case out <- h:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watchGARP uses netvip's BPF-filtered WatchGARP to notice when any other
+// node on the link claims the VIP over ARP, e.g. with the GARP a new master
+// sends on takeover. A master's periodic re-announcements are UDP hellos,
+// not GARPs, so they are not seen here.
+func (g *Group) watchGARP(ctx context.Context, notify chan<- struct{}, errCh chan<- error) {
+	err := netvip.WatchGARP(ctx, g.Interface, g.VIP.Addr(), func(pkt *arp.Packet) error {
+		if bytes.Equal(pkt.SenderHardwareAddr, g.Interface.HardwareAddr) {
+			return nil
+		}
+		select {
+		case notify <- struct{}{}:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		select {
+		case errCh <- err:
+		default:
+		}
+	}
+}
+
+// watchNDP is watchGARP's IPv6 counterpart: it uses netvip's BPF-filtered
+// WatchUnsolicitedNAFrames to notice when any node on the link claims the
+// VIP via Neighbor Advertisement.
+func (g *Group) watchNDP(ctx context.Context, notify chan<- struct{}, errCh chan<- error) {
+	err := netvip.WatchUnsolicitedNAFrames(ctx, g.Interface, g.VIP.Addr(), func(na *ndp.NeighborAdvertisement) error {
+		if naFromSelf(na, g.Interface) {
+			return nil
+		}
+		select {
+		case notify <- struct{}{}:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		select {
+		case errCh <- err:
+		default:
+		}
+	}
+}
+
+// naFromSelf reports whether na's Target Link-Layer Address option carries
+// intf's own hardware address, i.e. the advertisement is one this Group
+// itself sent via netvip.SendUnsolicitedNA.
+func naFromSelf(na *ndp.NeighborAdvertisement, intf *net.Interface) bool {
+	for _, opt := range na.Options {
+		lla, ok := opt.(*ndp.LinkLayerAddress)
+		if ok && lla.Direction == ndp.Target {
+			return bytes.Equal(lla.Addr, intf.HardwareAddr)
+		}
+	}
+	return false
+}
+
+// watchAddrChanges uses netvip.WatchAddrChanges to notice when the VIP
+// appears on or disappears from this Group's own interface, e.g. through a
+// conflicting tool or a stale lease left over from a previous run. Every
+// such change is reported, including the ones caused by this Group's own
+// takeover and relinquish.
+func (g *Group) watchAddrChanges(ctx context.Context, notify chan<- struct{}, errCh chan<- error) {
+	err := netvip.WatchAddrChanges(ctx, func(ev netvip.AddrEvent) error {
+		if ev.IfIndex != g.Interface.Index || ev.Prefix != g.VIP {
+			return nil
+		}
+		select {
+		case notify <- struct{}{}:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		select {
+		case errCh <- err:
+		default:
+		}
+	}
+}
+
+// shouldYieldTo reports whether h represents a peer this node must defer
+// to: either a strictly higher Priority, or an equal Priority broken by
+// comparing addresses, mirroring VRRP's own primary-IP-address tiebreak
+// (RFC 3768 ยง6.1). A node that has not set LocalAddr always yields on a
+// tie, which is the safe default: it avoids a permanent split-brain at
+// the cost of not contending ties in its own favor.
+func (g *Group) shouldYieldTo(h hello) bool {
+	if h.priority != g.Priority {
+		return h.priority > g.Priority
+	}
+	return h.from.Compare(g.LocalAddr) > 0
+}
+
+func (g *Group) isPeer(addr netip.Addr) bool {
+	for _, p := range g.Peers {
+		if p == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Group) port() int {
+	if g.Port != 0 {
+		return g.Port
+	}
+	return DefaultPort
+}
+
+func (g *Group) advertInterval() time.Duration {
+	if g.AdvertInterval > 0 {
+		return g.AdvertInterval
+	}
+	return DefaultAdvertInterval
+}
+
+// masterDownInterval mirrors RFC 3768's Master_Down_Interval: three
+// advertisement intervals plus a skew time of (256-Priority)/256 of an
+// advertisement interval, so higher-priority backups preempt sooner.
+func (g *Group) masterDownInterval() time.Duration {
+	advert := g.advertInterval()
+	skew := time.Duration(float64(256-int(g.Priority)) / 256 * float64(advert))
+	return 3*advert + skew
+}
+
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
@@ -0,0 +1,162 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hnakamur/netvip"
+)
+
+func TestGroupMasterDownInterval(t *testing.T) {
+	g := &Group{Priority: 255, AdvertInterval: time.Second}
+	if got, want := g.masterDownInterval(), 3*time.Second+3906250*time.Nanosecond; got != want {
+		t.Errorf("result mismatch for priority 255, got=%v, want=%v", got, want)
+	}
+
+	g = &Group{Priority: 0, AdvertInterval: time.Second}
+	if got, want := g.masterDownInterval(), 4*time.Second; got != want {
+		t.Errorf("result mismatch for priority 0, got=%v, want=%v", got, want)
+	}
+}
+
+func TestGroupIsPeer(t *testing.T) {
+	g := &Group{Peers: []netip.Addr{
+		netip.MustParseAddr("192.0.2.1"),
+		netip.MustParseAddr("192.0.2.2"),
+	}}
+
+	if got, want := g.isPeer(netip.MustParseAddr("192.0.2.1")), true; got != want {
+		t.Errorf("result mismatch for known peer, got=%v, want=%v", got, want)
+	}
+	if got, want := g.isPeer(netip.MustParseAddr("192.0.2.3")), false; got != want {
+		t.Errorf("result mismatch for unknown peer, got=%v, want=%v", got, want)
+	}
+}
+
+func TestGroupShouldYieldTo(t *testing.T) {
+	g := &Group{Priority: 100, LocalAddr: netip.MustParseAddr("192.0.2.5")}
+
+	if got, want := g.shouldYieldTo(hello{priority: 150, from: netip.MustParseAddr("192.0.2.1")}), true; got != want {
+		t.Errorf("result mismatch for higher priority peer, got=%v, want=%v", got, want)
+	}
+	if got, want := g.shouldYieldTo(hello{priority: 50, from: netip.MustParseAddr("192.0.2.9")}), false; got != want {
+		t.Errorf("result mismatch for lower priority peer, got=%v, want=%v", got, want)
+	}
+	if got, want := g.shouldYieldTo(hello{priority: 100, from: netip.MustParseAddr("192.0.2.9")}), true; got != want {
+		t.Errorf("result mismatch for tied priority with greater address, got=%v, want=%v", got, want)
+	}
+	if got, want := g.shouldYieldTo(hello{priority: 100, from: netip.MustParseAddr("192.0.2.1")}), false; got != want {
+		t.Errorf("result mismatch for tied priority with lesser address, got=%v, want=%v", got, want)
+	}
+}
+
+func TestGroupPortAndAdvertIntervalDefaults(t *testing.T) {
+	g := &Group{}
+	if got, want := g.port(), DefaultPort; got != want {
+		t.Errorf("port mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := g.advertInterval(), DefaultAdvertInterval; got != want {
+		t.Errorf("advertInterval mismatch, got=%v, want=%v", got, want)
+	}
+
+	g = &Group{Port: 7000, AdvertInterval: 2 * time.Second}
+	if got, want := g.port(), 7000; got != want {
+		t.Errorf("port mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := g.advertInterval(), 2*time.Second; got != want {
+		t.Errorf("advertInterval mismatch, got=%v, want=%v", got, want)
+	}
+}
+
+// TestGroupRunTransitions drives Run's state machine end to end: a Group
+// with no peers yet heard from should preempt to Master, and should step
+// back down to Backup on a hello from a higher-priority peer. takeover and
+// relinquish are stubbed out via the replaceAddr/delAddr/sendGARP seams so
+// the test needs neither a real interface nor root.
+func TestGroupRunTransitions(t *testing.T) {
+	origReplaceAddr, origDelAddr, origSendGARP := replaceAddr, delAddr, sendGARP
+	t.Cleanup(func() {
+		replaceAddr, delAddr, sendGARP = origReplaceAddr, origDelAddr, origSendGARP
+	})
+
+	var takeovers, relinquishes int32
+	replaceAddr = func(intf *net.Interface, p netip.Prefix, label string, opts *netvip.AddrOptions) error {
+		atomic.AddInt32(&takeovers, 1)
+		return nil
+	}
+	delAddr = func(intf *net.Interface, p netip.Prefix) error {
+		atomic.AddInt32(&relinquishes, 1)
+		return nil
+	}
+	sendGARP = func(intf *net.Interface, addr netip.Addr) error {
+		return nil
+	}
+
+	const port = 53287
+	peer := netip.MustParseAddr("127.0.0.1")
+	g := &Group{
+		Interface:      &net.Interface{Index: 1, Name: "lo"},
+		VIP:            netip.MustParsePrefix("192.0.2.250/32"),
+		Priority:       100,
+		LocalAddr:      peer,
+		Peers:          []netip.Addr{peer},
+		Port:           port,
+		AdvertInterval: 30 * time.Millisecond,
+	}
+
+	becameMaster := make(chan struct{}, 1)
+	becameBackup := make(chan struct{}, 1)
+	g.OnBecomeMaster = func() { becameMaster <- struct{}{} }
+	g.OnBecomeBackup = func() { becameBackup <- struct{}{} }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- g.Run(ctx) }()
+
+	select {
+	case <-becameMaster:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for Group to preempt to Master")
+	}
+	if got, want := atomic.LoadInt32(&takeovers), int32(1); got != want {
+		t.Errorf("takeover count mismatch, got=%d, want=%d", got, want)
+	}
+
+	conn, err := net.Dial("udp", net.JoinHostPort(peer.String(), strconv.Itoa(port)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	vipBytes, err := g.VIP.Addr().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write(append([]byte{200}, vipBytes...)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-becameBackup:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for Group to step down to Backup")
+	}
+
+	cancel()
+	select {
+	case err := <-runErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Run error mismatch, got=%v, want=%v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for Run to return after cancel")
+	}
+	if got, want := atomic.LoadInt32(&relinquishes), int32(0); got != want {
+		t.Errorf("relinquish count mismatch, got=%d, want=%d (group had already stepped down to Backup)", got, want)
+	}
+}
@@ -3,13 +3,17 @@ package netvip
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"net"
 	"net/netip"
 	"syscall"
 
 	"github.com/mdlayher/arp"
 	"github.com/mdlayher/ethernet"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
 )
 
 var macAddrBroadcast = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
@@ -66,7 +70,17 @@ func IsGARPPacket(p *arp.Packet, vip netip.Addr) bool {
 		p.TargetIP.Compare(vip) == 0
 }
 
-func WatchGARP(ctx context.Context, addr netip.Addr, callback func(*arp.Packet) error) error {
+// WatchGARP watches intf for GARP packets announcing addr and invokes
+// callback for each one received. A classic BPF program is attached to the
+// socket so the kernel drops every frame that isn't an ARP request for
+// addr before the process is even woken up, and the socket is bound to
+// intf so frames from other NICs are never observed.
+func WatchGARP(ctx context.Context, intf *net.Interface, addr netip.Addr, callback func(*arp.Packet) error) error {
+	filter, err := garpFilter(addr)
+	if err != nil {
+		return err
+	}
+
 	const typ = syscall.SOCK_RAW | syscall.SOCK_CLOEXEC
 	proto := int(htons(uint16(syscall.ETH_P_ARP)))
 	fd, err := syscall.Socket(syscall.AF_PACKET, typ, proto)
@@ -75,6 +89,18 @@ func WatchGARP(ctx context.Context, addr netip.Addr, callback func(*arp.Packet)
 	}
 	defer syscall.Close(fd)
 
+	if err := attachFilter(fd, filter); err != nil {
+		return err
+	}
+
+	sll := &syscall.SockaddrLinklayer{
+		Protocol: htons(uint16(syscall.ETH_P_ARP)),
+		Ifindex:  intf.Index,
+	}
+	if err := syscall.Bind(fd, sll); err != nil {
+		return err
+	}
+
 	buf := make([]byte, 1500)
 	for {
 		select {
@@ -106,3 +132,51 @@ func WatchGARP(ctx context.Context, addr netip.Addr, callback func(*arp.Packet)
 		}
 	}
 }
+
+// garpFilter builds a classic BPF program that accepts only ARP requests
+// where both the sender and target IP equal vip, so the kernel can drop
+// uninteresting frames on a busy L2 before WatchGARP is woken up. vip must
+// be an IPv4 address; ARP has no IPv6 equivalent (that is NDP's job, see
+// WatchUnsolicitedNAFrames).
+func garpFilter(vip netip.Addr) ([]bpf.Instruction, error) {
+	if !vip.Is4() {
+		return nil, fmt.Errorf("netvip: garpFilter: %s is not an IPv4 address", vip)
+	}
+	v4 := vip.As4()
+	vipU32 := binary.BigEndian.Uint32(v4[:])
+
+	return []bpf.Instruction{
+		// ether_type at offset 12 must be ARP (0x0806).
+		bpf.LoadAbsolute{Off: 12, Size: 2},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: 0x0806, SkipFalse: 7},
+		// ARP oper at offset 20 must be request (1).
+		bpf.LoadAbsolute{Off: 20, Size: 2},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: 1, SkipFalse: 5},
+		// sender IP at offset 28 must equal vip.
+		bpf.LoadAbsolute{Off: 28, Size: 4},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: vipU32, SkipFalse: 3},
+		// target IP at offset 38 must equal vip.
+		bpf.LoadAbsolute{Off: 38, Size: 4},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: vipU32, SkipFalse: 1},
+		bpf.RetConstant{Val: 0xffff},
+		bpf.RetConstant{Val: 0},
+	}, nil
+}
+
+// attachFilter assembles insns into a classic BPF program and attaches it
+// to fd via setsockopt(SO_ATTACH_FILTER).
+func attachFilter(fd int, insns []bpf.Instruction) error {
+	raw, err := bpf.Assemble(insns)
+	if err != nil {
+		return err
+	}
+	filter := make([]unix.SockFilter, len(raw))
+	for i, ins := range raw {
+		filter[i] = unix.SockFilter{Code: ins.Op, Jt: ins.Jt, Jf: ins.Jf, K: ins.K}
+	}
+	prog := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+	return unix.SetsockoptSockFprog(fd, unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, &prog)
+}
@@ -0,0 +1,73 @@
+package netvip
+
+import (
+	"net/netip"
+	"testing"
+
+	"golang.org/x/net/bpf"
+)
+
+// buildARPFrame constructs a minimal Ethernet+ARP frame (42 bytes) with the
+// given EtherType, ARP operation, sender and target protocol addresses, so
+// garpFilter's hand-computed offsets can be exercised against a real frame.
+func buildARPFrame(etherType uint16, oper uint16, sender, target [4]byte) []byte {
+	f := make([]byte, 42)
+	f[12] = byte(etherType >> 8)
+	f[13] = byte(etherType)
+	f[14], f[15] = 0, 1 // HTYPE: Ethernet
+	f[16], f[17] = 8, 0 // PTYPE: IPv4
+	f[18], f[19] = 6, 4 // HLEN, PLEN
+	f[20] = byte(oper >> 8)
+	f[21] = byte(oper)
+	copy(f[28:32], sender[:])
+	copy(f[38:42], target[:])
+	return f
+}
+
+func TestGARPFilter(t *testing.T) {
+	vip := netip.MustParseAddr("192.0.2.10")
+	insns, err := garpFilter(vip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bpf.Assemble(insns); err != nil {
+		t.Fatal(err)
+	}
+	vm, err := bpf.NewVM(insns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v4 := vip.As4()
+	other := [4]byte{198, 51, 100, 1}
+
+	good := buildARPFrame(0x0806, 1, v4, v4)
+	if n, err := vm.Run(good); err != nil {
+		t.Fatal(err)
+	} else if n == 0 {
+		t.Error("filter rejected a GARP request for vip, want accept")
+	}
+
+	bad := []struct {
+		name  string
+		frame []byte
+	}{
+		{"wrong ethertype", buildARPFrame(0x0800, 1, v4, v4)},
+		{"wrong operation", buildARPFrame(0x0806, 2, v4, v4)},
+		{"wrong sender IP", buildARPFrame(0x0806, 1, other, v4)},
+		{"wrong target IP", buildARPFrame(0x0806, 1, v4, other)},
+	}
+	for _, tc := range bad {
+		if n, err := vm.Run(tc.frame); err != nil {
+			t.Fatal(err)
+		} else if n != 0 {
+			t.Errorf("%s: filter accepted a frame it should reject", tc.name)
+		}
+	}
+}
+
+func TestGARPFilterRejectsNonIPv4(t *testing.T) {
+	if _, err := garpFilter(netip.MustParseAddr("2001:db8::1")); err == nil {
+		t.Error("expected an error for an IPv6 address")
+	}
+}
@@ -0,0 +1,244 @@
+package netvip
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/mdlayher/ethernet"
+	"github.com/mdlayher/ndp"
+	"golang.org/x/net/bpf"
+)
+
+// DefaultNeighborAdvertisements is the default number of times
+// SendUnsolicitedNA (re)transmits an unsolicited Neighbor Advertisement.
+// It corresponds to MAX_NEIGHBOR_ADVERTISEMENT from RFC 4861 section 7.2.6.
+const DefaultNeighborAdvertisements = 3
+
+// DefaultRetransTimer is the default interval between retransmissions of
+// an unsolicited Neighbor Advertisement, per RFC 4861 section 7.2.6.
+const DefaultRetransTimer = time.Second
+
+// UnsolicitedNAOptions controls how SendUnsolicitedNA (re)transmits an
+// unsolicited Neighbor Advertisement. The zero value uses the RFC 4861
+// section 7.2.6 defaults.
+type UnsolicitedNAOptions struct {
+	// Count is the number of times the advertisement is sent. It
+	// defaults to DefaultNeighborAdvertisements if zero.
+	Count int
+
+	// RetransTimer is the interval between retransmissions. It defaults
+	// to DefaultRetransTimer if zero.
+	RetransTimer time.Duration
+
+	// Router sets the Router (R) flag on the advertisement, indicating
+	// the sender is a router.
+	Router bool
+}
+
+func (o *UnsolicitedNAOptions) countOrDefault() int {
+	if o == nil || o.Count <= 0 {
+		return DefaultNeighborAdvertisements
+	}
+	return o.Count
+}
+
+func (o *UnsolicitedNAOptions) retransTimerOrDefault() time.Duration {
+	if o == nil || o.RetransTimer <= 0 {
+		return DefaultRetransTimer
+	}
+	return o.RetransTimer
+}
+
+// naAllNodesMulticast is the IPv6 all-nodes link-local multicast address
+// (ff02::1), the destination for unsolicited Neighbor Advertisements.
+var naAllNodesMulticast = netip.MustParseAddr("ff02::1")
+
+// SendUnsolicitedNA sends an unsolicited Neighbor Advertisement (ICMPv6
+// type 136) for addr on intf, the IPv6 equivalent of SendGARP. It is used
+// during VIP failover so that other nodes on the link update their
+// neighbor cache for the VIP without waiting for a Neighbor Solicitation.
+//
+// The advertisement is sent to the all-nodes multicast address ff02::1
+// with the Override (O) flag set and a Target Link-Layer Address option
+// carrying intf.HardwareAddr, as recommended by RFC 4861 section 7.2.6.
+// opts controls the Router flag and the retransmission count/interval;
+// a nil opts uses the RFC defaults.
+func SendUnsolicitedNA(intf *net.Interface, addr netip.Addr, opts *UnsolicitedNAOptions) error {
+	c, _, err := ndp.Listen(intf, ndp.LinkLocal)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	m := &ndp.NeighborAdvertisement{
+		Router:        opts != nil && opts.Router,
+		Solicited:     false,
+		Override:      true,
+		TargetAddress: addr,
+		Options: []ndp.Option{
+			&ndp.LinkLayerAddress{
+				Direction: ndp.Target,
+				Addr:      intf.HardwareAddr,
+			},
+		},
+	}
+
+	count := opts.countOrDefault()
+	retrans := opts.retransTimerOrDefault()
+	for i := 0; i < count; i++ {
+		if err := c.WriteTo(m, nil, naAllNodesMulticast); err != nil {
+			return err
+		}
+		if i < count-1 {
+			time.Sleep(retrans)
+		}
+	}
+	return nil
+}
+
+// IsUnsolicitedNA reports whether m is an unsolicited Neighbor
+// Advertisement for vip, i.e. one sent with the Override flag set and not
+// as a solicited response.
+func IsUnsolicitedNA(m *ndp.NeighborAdvertisement, vip netip.Addr) bool {
+	return !m.Solicited && m.Override && m.TargetAddress == vip
+}
+
+// WatchUnsolicitedNA watches intf for unsolicited Neighbor Advertisements
+// for addr and invokes callback for each one received. It is the IPv6
+// counterpart of WatchGARP.
+func WatchUnsolicitedNA(ctx context.Context, intf *net.Interface, addr netip.Addr, callback func(*ndp.NeighborAdvertisement) error) error {
+	c, _, err := ndp.Listen(intf, ndp.LinkLocal)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err := c.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			return err
+		}
+		msg, _, _, err := c.ReadFrom()
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			return err
+		}
+		na, ok := msg.(*ndp.NeighborAdvertisement)
+		if !ok {
+			continue
+		}
+		if IsUnsolicitedNA(na, addr) {
+			if err := callback(na); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ipv6HeaderLen is the length of the fixed IPv6 header, i.e. the offset of
+// the upper-layer payload within an IPv6 packet that carries no extension
+// headers.
+const ipv6HeaderLen = 40
+
+// WatchUnsolicitedNAFrames is the AF_PACKET-based, BPF-filtered
+// counterpart to WatchUnsolicitedNA: like WatchGARP, it attaches a classic
+// BPF program so the kernel drops every frame that isn't an ICMPv6
+// Neighbor Advertisement (type 136) before the process is woken up, and
+// binds the socket to intf so frames from other NICs are never observed.
+// Captured frames are decoded with ndp.ParseMessage.
+func WatchUnsolicitedNAFrames(ctx context.Context, intf *net.Interface, addr netip.Addr, callback func(*ndp.NeighborAdvertisement) error) error {
+	const typ = syscall.SOCK_RAW | syscall.SOCK_CLOEXEC
+	proto := int(htons(uint16(syscall.ETH_P_IPV6)))
+	fd, err := syscall.Socket(syscall.AF_PACKET, typ, proto)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	if err := attachFilter(fd, ndpFilter()); err != nil {
+		return err
+	}
+
+	sll := &syscall.SockaddrLinklayer{
+		Protocol: htons(uint16(syscall.ETH_P_IPV6)),
+		Ifindex:  intf.Index,
+	}
+	if err := syscall.Bind(fd, sll); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		tv := &syscall.Timeval{Sec: 1, Usec: 0}
+		if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, tv); err != nil {
+			return err
+		}
+
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return err
+		}
+
+		f := new(ethernet.Frame)
+		if err := f.UnmarshalBinary(buf[:n]); err != nil {
+			return err
+		}
+		if f.EtherType != ethernet.EtherTypeIPv6 || len(f.Payload) < ipv6HeaderLen {
+			continue
+		}
+		msg, err := ndp.ParseMessage(f.Payload[ipv6HeaderLen:])
+		if err != nil {
+			continue
+		}
+		na, ok := msg.(*ndp.NeighborAdvertisement)
+		if !ok {
+			continue
+		}
+		if IsUnsolicitedNA(na, addr) {
+			if err := callback(na); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ndpFilter builds a classic BPF program that accepts only IPv6 frames
+// whose next header is ICMPv6 and whose ICMPv6 type is 136 (Neighbor
+// Advertisement), so WatchUnsolicitedNAFrames is only woken up for NDP
+// traffic.
+func ndpFilter() []bpf.Instruction {
+	return []bpf.Instruction{
+		// ether_type at offset 12 must be IPv6 (0x86dd).
+		bpf.LoadAbsolute{Off: 12, Size: 2},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: 0x86dd, SkipFalse: 5},
+		// IPv6 next header at offset 20 must be ICMPv6 (58).
+		bpf.LoadAbsolute{Off: 20, Size: 1},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: 58, SkipFalse: 3},
+		// ICMPv6 type at offset 54 must be Neighbor Advertisement (136).
+		bpf.LoadAbsolute{Off: 54, Size: 1},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: 136, SkipFalse: 1},
+		bpf.RetConstant{Val: 0xffff},
+		bpf.RetConstant{Val: 0},
+	}
+}
@@ -0,0 +1,53 @@
+package netvip
+
+import (
+	"testing"
+
+	"golang.org/x/net/bpf"
+)
+
+// buildIPv6Frame constructs a minimal Ethernet+IPv6 frame (14+40+1 bytes)
+// with the given EtherType, IPv6 next header and ICMPv6 type, so ndpFilter's
+// hand-computed offsets can be exercised against a real frame.
+func buildIPv6Frame(etherType uint16, nextHeader uint8, icmpType uint8) []byte {
+	f := make([]byte, 14+ipv6HeaderLen+1)
+	f[12] = byte(etherType >> 8)
+	f[13] = byte(etherType)
+	f[14+6] = nextHeader
+	f[14+ipv6HeaderLen] = icmpType
+	return f
+}
+
+func TestNDPFilter(t *testing.T) {
+	insns := ndpFilter()
+	if _, err := bpf.Assemble(insns); err != nil {
+		t.Fatal(err)
+	}
+	vm, err := bpf.NewVM(insns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	good := buildIPv6Frame(0x86dd, 58, 136)
+	if n, err := vm.Run(good); err != nil {
+		t.Fatal(err)
+	} else if n == 0 {
+		t.Error("filter rejected a Neighbor Advertisement, want accept")
+	}
+
+	bad := []struct {
+		name  string
+		frame []byte
+	}{
+		{"wrong ethertype", buildIPv6Frame(0x0800, 58, 136)},
+		{"wrong next header", buildIPv6Frame(0x86dd, 17, 136)},
+		{"wrong ICMPv6 type", buildIPv6Frame(0x86dd, 58, 135)},
+	}
+	for _, tc := range bad {
+		if n, err := vm.Run(tc.frame); err != nil {
+			t.Fatal(err)
+		} else if n != 0 {
+			t.Errorf("%s: filter accepted a frame it should reject", tc.name)
+		}
+	}
+}
@@ -3,160 +3,183 @@ package netvip
 import (
 	"net"
 	"net/netip"
-	"syscall"
-	"unsafe"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
 )
 
+// dialNetlinkRoute opens a NETLINK_ROUTE connection. It is a variable so
+// tests can substitute github.com/mdlayher/netlink/nltest's in-process fake
+// instead of a real netlink socket.
+var dialNetlinkRoute = func(cfg *netlink.Config) (*netlink.Conn, error) {
+	return netlink.Dial(unix.NETLINK_ROUTE, cfg)
+}
+
 // AddAddr adds the specified IP address prefix (CIDR) to the interface.
-func AddAddr(intf *net.Interface, p netip.Prefix, label string) error {
-	return addOrDelAddr(intf.Index, syscall.RTM_NEWADDR, p, label,
-		syscall.NLM_F_CREATE|syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+// opts may be nil.
+func AddAddr(intf *net.Interface, p netip.Prefix, label string, opts *AddrOptions) error {
+	return addOrDelAddr(intf.Index, unix.RTM_NEWADDR, p, label,
+		netlink.Create|netlink.Excl, opts)
+}
+
+// ReplaceAddr idempotently installs the specified IP address prefix (CIDR)
+// on the interface: unlike AddAddr, it succeeds even if the address is
+// already present, by sending RTM_NEWADDR with NLM_F_REPLACE instead of
+// NLM_F_EXCL. opts may be nil.
+func ReplaceAddr(intf *net.Interface, p netip.Prefix, label string, opts *AddrOptions) error {
+	return addOrDelAddr(intf.Index, unix.RTM_NEWADDR, p, label,
+		netlink.Create|netlink.Replace, opts)
 }
 
 // DelAddr deletes the specified IP address prefix (CIDR) from the interface.
 func DelAddr(intf *net.Interface, p netip.Prefix) error {
-	return addOrDelAddr(intf.Index, syscall.RTM_DELADDR, p, "",
-		syscall.NLM_F_ACK)
+	return addOrDelAddr(intf.Index, unix.RTM_DELADDR, p, "", 0, nil)
+}
+
+// InterfaceAddPrefix adds the address prefix (CIDR) to the interface,
+// applying label if non-empty. It is a thin wrapper around AddAddr kept
+// for API compatibility with callers predating the move to
+// github.com/mdlayher/netlink.
+func InterfaceAddPrefix(intf *net.Interface, p netip.Prefix, label string) error {
+	return AddAddr(intf, p, label, nil)
+}
+
+// InterfaceDelPrefix deletes the address prefix (CIDR) from the interface.
+// It is a thin wrapper around DelAddr kept for API compatibility with
+// callers predating the move to github.com/mdlayher/netlink.
+func InterfaceDelPrefix(intf *net.Interface, p netip.Prefix) error {
+	return DelAddr(intf, p)
+}
+
+// AddrOptions carries optional extended netlink attributes for AddAddr and
+// ReplaceAddr. The zero value (or a nil *AddrOptions) adds a plain address
+// with neither attribute set.
+type AddrOptions struct {
+	// Flags sets the extended IFA_FLAGS attribute when non-zero, e.g.
+	// IFA_F_NODAD to suppress duplicate address detection or
+	// IFA_F_HOMEADDRESS/IFA_F_MANAGETEMPADDR/IFA_F_NOPREFIXROUTE.
+	Flags uint32
+
+	// CacheInfo sets the IFA_CACHEINFO attribute when non-nil, giving the
+	// address a preferred and valid lifetime. This is useful for IPv6
+	// SLAAC-aware deployments and for mirroring addresses learned from
+	// router advertisements.
+	CacheInfo *CacheInfo
+}
+
+// CacheInfo mirrors the kernel's struct ifa_cacheinfo and is carried in
+// AddrOptions.CacheInfo to set the preferred/valid lifetimes of an address.
+type CacheInfo struct {
+	Preferred uint32
+	Valid     uint32
+	CStamp    uint32
+	TStamp    uint32
 }
 
-func addOrDelAddr(ifIndex int, proto uint16, p netip.Prefix, label string, flags uint16) error {
-	s, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW, syscall.NETLINK_ROUTE)
+// Extended address flags for the IFA_FLAGS attribute (AddrOptions.Flags).
+// See linux/if_addr.h.
+const (
+	IFA_F_NODAD          = 0x02
+	IFA_F_HOMEADDRESS    = 0x10
+	IFA_F_MANAGETEMPADDR = 0x100
+	IFA_F_NOPREFIXROUTE  = 0x200
+)
+
+func addOrDelAddr(ifIndex int, msgType uint16, p netip.Prefix, label string, flags netlink.HeaderFlags, opts *AddrOptions) error {
+	c, err := dialNetlinkRoute(nil)
 	if err != nil {
 		return err
 	}
-	defer syscall.Close(s)
-	lsa := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}
-	if err := syscall.Bind(s, lsa); err != nil {
-		return err
-	}
-	req, err := buildAddOrDelAddrReq(ifIndex, proto, p, label, flags)
+	defer c.Close()
+
+	data, err := buildIfAddrMessage(ifIndex, p, label, opts)
 	if err != nil {
 		return err
 	}
 
-	if err := syscall.Sendto(s, req, 0, lsa); err != nil {
-		return err
-	}
-	rb := make([]byte, syscall.Getpagesize())
-done:
-	for {
-		nr, _, err := syscall.Recvfrom(s, rb, 0)
-		if err != nil {
-			return err
-		}
-		if nr < syscall.NLMSG_HDRLEN {
-			return syscall.EINVAL
-		}
-		msgs, err := syscall.ParseNetlinkMessage(rb[:nr])
-		if err != nil {
-			return err
-		}
-		for _, msg := range msgs {
-			if msg.Header.Type == syscall.NLMSG_DONE {
-				break done
-			}
-			if msg.Header.Type == syscall.NLMSG_ERROR {
-				errCode := *(*int32)(unsafe.Pointer(&msg.Data[:4][0]))
-				if errCode == 0 {
-					break done
-				}
-				return syscall.Errno(-errCode)
-			}
-		}
+	req := netlink.Message{
+		Header: netlink.Header{
+			Type:  netlink.HeaderType(msgType),
+			Flags: netlink.Request | netlink.Acknowledge | flags,
+		},
+		Data: data,
 	}
-	return nil
+	_, err = c.Execute(req)
+	return err
 }
 
-func buildAddOrDelAddrReq(ifIndex int, proto uint16, p netip.Prefix, label string, flags uint16) ([]byte, error) {
+func buildIfAddrMessage(ifIndex int, p netip.Prefix, label string, opts *AddrOptions) ([]byte, error) {
 	addr := p.Addr()
-	addrByteLen := addr.BitLen() / 8
-	isIPv4 := addr.Is4()
-
-	reqLen := syscall.SizeofNlMsghdr + syscall.SizeofIfAddrmsg +
-		2*(syscall.SizeofRtAttr+addrByteLen)
-	var labelPaddedLen int
-	if label != "" {
-		labelPaddedLen = alignNlAttr(len(label) + 1)
-		reqLen += syscall.SizeofRtAttr + labelPaddedLen
+	family := uint8(unix.AF_INET6)
+	if addr.Is4() {
+		family = unix.AF_INET
 	}
-	req := make([]byte, reqLen)
-	dest := req
-
-	hdr := &syscall.NlMsghdr{
-		Len:   uint32(reqLen),
-		Type:  proto,
-		Flags: syscall.NLM_F_REQUEST | flags,
-		Seq:   1,
+
+	hdr := ifAddrmsg{
+		Family:    family,
+		Prefixlen: uint8(p.Bits()),
+		Index:     uint32(ifIndex),
 	}
-	dest = serializeNlMsghdr(dest, hdr)
 
-	msg := new(syscall.IfAddrmsg)
-	if isIPv4 {
-		msg.Family = syscall.AF_INET
-	} else {
-		msg.Family = syscall.AF_INET6
+	ae := netlink.NewAttributeEncoder()
+	ae.Bytes(unix.IFA_LOCAL, addr.AsSlice())
+	ae.Bytes(unix.IFA_ADDRESS, addr.AsSlice())
+	if label != "" {
+		ae.String(unix.IFA_LABEL, label)
 	}
-	prefixlen := p.Bits()
-	msg.Prefixlen = uint8(prefixlen)
-	msg.Index = uint32(ifIndex)
-	dest = serializeIfAddrmsg(dest, msg)
-
-	attr := &syscall.RtAttr{
-		Len:  syscall.SizeofRtAttr + uint16(addrByteLen),
-		Type: syscall.IFA_LOCAL,
+	if opts != nil && opts.Flags != 0 {
+		ae.Uint32(unix.IFA_FLAGS, opts.Flags)
 	}
-	dest = serializeRtAttr(dest, attr, addr.AsSlice())
-
-	attr = &syscall.RtAttr{
-		Len:  syscall.SizeofRtAttr + uint16(addrByteLen),
-		Type: syscall.IFA_ADDRESS,
+	if opts != nil && opts.CacheInfo != nil {
+		ae.Bytes(unix.IFA_CACHEINFO, serializeCacheInfo(opts.CacheInfo))
 	}
-	dest = serializeRtAttr(dest, attr, addr.AsSlice())
-
-	if label != "" {
-		attr = &syscall.RtAttr{
-			Len:  syscall.SizeofRtAttr + uint16(labelPaddedLen),
-			Type: syscall.IFA_LABEL,
-		}
-		_ = serializeRtAttr(dest, attr, []byte(label), []byte{'\x00'})
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
 	}
 
-	return req, nil
+	return append(hdr.serialize(), attrs...), nil
 }
 
-func serializeNlMsghdr(b []byte, hdr *syscall.NlMsghdr) []byte {
-	*(*uint32)(unsafe.Pointer(&b[0:4][0])) = hdr.Len
-	*(*uint16)(unsafe.Pointer(&b[4:6][0])) = hdr.Type
-	*(*uint16)(unsafe.Pointer(&b[6:8][0])) = hdr.Flags
-	*(*uint32)(unsafe.Pointer(&b[8:12][0])) = hdr.Seq
-	*(*uint32)(unsafe.Pointer(&b[12:16][0])) = hdr.Pid
-	return b[syscall.SizeofIfAddrmsg:]
+func serializeCacheInfo(ci *CacheInfo) []byte {
+	b := make([]byte, 16)
+	nlenc.PutUint32(b[0:4], ci.Preferred)
+	nlenc.PutUint32(b[4:8], ci.Valid)
+	nlenc.PutUint32(b[8:12], ci.CStamp)
+	nlenc.PutUint32(b[12:16], ci.TStamp)
+	return b
 }
 
-func serializeIfAddrmsg(b []byte, msg *syscall.IfAddrmsg) []byte {
-	*(*uint8)(unsafe.Pointer(&b[0])) = msg.Family
-	*(*uint8)(unsafe.Pointer(&b[1])) = msg.Prefixlen
-	*(*uint8)(unsafe.Pointer(&b[2])) = msg.Flags
-	*(*uint8)(unsafe.Pointer(&b[3])) = msg.Scope
-	*(*uint32)(unsafe.Pointer(&b[4:8][0])) = msg.Index
-	return b[syscall.SizeofIfAddrmsg:]
+// ifAddrmsgLen is the length of the kernel's struct ifaddrmsg, the fixed
+// header that precedes the rtattr attributes in an RTM_*ADDR message.
+const ifAddrmsgLen = 8
+
+// ifAddrmsg mirrors the kernel's struct ifaddrmsg.
+type ifAddrmsg struct {
+	Family    uint8
+	Prefixlen uint8
+	Flags     uint8
+	Scope     uint8
+	Index     uint32
 }
 
-func serializeRtAttr(b []byte, attr *syscall.RtAttr, data ...[]byte) []byte {
-	*(*uint16)(unsafe.Pointer(&b[0:2][0])) = attr.Len
-	*(*uint16)(unsafe.Pointer(&b[2:4][0])) = attr.Type
-	p := b[4:]
-	for _, d := range data {
-		copy(p, d)
-		p = p[len(d):]
-	}
-	return b[attr.Len:]
-}
-
-func alignNlAttr(size int) int {
-	return align(size, syscall.NLA_ALIGNTO)
+func (h ifAddrmsg) serialize() []byte {
+	b := make([]byte, ifAddrmsgLen)
+	b[0] = h.Family
+	b[1] = h.Prefixlen
+	b[2] = h.Flags
+	b[3] = h.Scope
+	nlenc.PutUint32(b[4:8], h.Index)
+	return b
 }
 
-func align(size, tick int) int {
-	return (size + tick - 1) &^ (tick - 1)
+func parseIfAddrmsg(b []byte) ifAddrmsg {
+	return ifAddrmsg{
+		Family:    b[0],
+		Prefixlen: b[1],
+		Flags:     b[2],
+		Scope:     b[3],
+		Index:     nlenc.Uint32(b[4:8]),
+	}
 }
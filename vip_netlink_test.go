@@ -0,0 +1,128 @@
+package netvip
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+	"golang.org/x/sys/unix"
+)
+
+// fakeNetlinkRoute substitutes dialNetlinkRoute with mdlayher/netlink's
+// in-process fake for the duration of the test, so AddAddr/DelAddr/
+// ReplaceAddr can be exercised without a real netlink socket or root.
+func fakeNetlinkRoute(t *testing.T, fn nltest.Func) *netlink.Message {
+	t.Helper()
+
+	var got netlink.Message
+	orig := dialNetlinkRoute
+	t.Cleanup(func() { dialNetlinkRoute = orig })
+	dialNetlinkRoute = func(cfg *netlink.Config) (*netlink.Conn, error) {
+		return nltest.Dial(func(reqs []netlink.Message) ([]netlink.Message, error) {
+			got = reqs[0]
+			if fn != nil {
+				return fn(reqs)
+			}
+			return nltest.Error(0, reqs)
+		}), nil
+	}
+	return &got
+}
+
+func TestAddAddrRequest(t *testing.T) {
+	vip := netip.MustParsePrefix("192.0.2.10/32")
+	intf := &net.Interface{Index: 7, Name: "eth0"}
+	got := fakeNetlinkRoute(t, nil)
+
+	if err := AddAddr(intf, vip, "eth0:0", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := netlink.HeaderType(unix.RTM_NEWADDR); got.Header.Type != want {
+		t.Errorf("message type mismatch, got=%d, want=%d", got.Header.Type, want)
+	}
+	if want := netlink.Request | netlink.Acknowledge | netlink.Create | netlink.Excl; got.Header.Flags != want {
+		t.Errorf("flags mismatch, got=%#x, want=%#x", got.Header.Flags, want)
+	}
+
+	hdr := parseIfAddrmsg(got.Data)
+	if want := uint32(intf.Index); hdr.Index != want {
+		t.Errorf("ifindex mismatch, got=%d, want=%d", hdr.Index, want)
+	}
+
+	ad, err := netlink.NewAttributeDecoder(got.Data[ifAddrmsgLen:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sawLabel bool
+	for ad.Next() {
+		if ad.Type() == unix.IFA_LABEL {
+			sawLabel = true
+			if got, want := ad.String(), "eth0:0"; got != want {
+				t.Errorf("label mismatch, got=%q, want=%q", got, want)
+			}
+		}
+	}
+	if err := ad.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !sawLabel {
+		t.Error("IFA_LABEL attribute not found in request")
+	}
+}
+
+func TestReplaceAddrUsesReplaceFlag(t *testing.T) {
+	vip := netip.MustParsePrefix("192.0.2.10/32")
+	intf := &net.Interface{Index: 7, Name: "eth0"}
+	got := fakeNetlinkRoute(t, nil)
+
+	if err := ReplaceAddr(intf, vip, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := netlink.Request | netlink.Acknowledge | netlink.Create | netlink.Replace; got.Header.Flags != want {
+		t.Errorf("flags mismatch, got=%#x, want=%#x", got.Header.Flags, want)
+	}
+}
+
+func TestAddAddrRequestWithOptions(t *testing.T) {
+	vip := netip.MustParsePrefix("2001:db8::1/64")
+	intf := &net.Interface{Index: 3, Name: "eth1"}
+	got := fakeNetlinkRoute(t, nil)
+
+	opts := &AddrOptions{
+		Flags:     IFA_F_NODAD,
+		CacheInfo: &CacheInfo{Preferred: 300, Valid: 600},
+	}
+	if err := AddAddr(intf, vip, "", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	ad, err := netlink.NewAttributeDecoder(got.Data[ifAddrmsgLen:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sawFlags, sawCacheInfo bool
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.IFA_FLAGS:
+			sawFlags = true
+			if got, want := ad.Uint32(), uint32(IFA_F_NODAD); got != want {
+				t.Errorf("IFA_FLAGS mismatch, got=%#x, want=%#x", got, want)
+			}
+		case unix.IFA_CACHEINFO:
+			sawCacheInfo = true
+		}
+	}
+	if err := ad.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !sawFlags {
+		t.Error("IFA_FLAGS attribute not found in request")
+	}
+	if !sawCacheInfo {
+		t.Error("IFA_CACHEINFO attribute not found in request")
+	}
+}
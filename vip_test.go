@@ -1,54 +1,39 @@
 package netvip
 
 import (
-	"flag"
 	"net"
 	"net/netip"
 	"testing"
-)
 
-var testInterfName = flag.String("interf", "enp1s0f1", "network interface name for test")
-var testVIPStr = flag.String("vip", "192.168.2.248/32", "virtual IP address prefix (CIDR) for test")
-var testLabel = flag.String("label", "enp1s0f1:0", "label for virtual address for test")
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
 
+// TestAddDelAddr drives AddAddr and DelAddr against mdlayher/netlink's
+// in-process fake, so it needs neither a real interface nor root.
 func TestAddDelAddr(t *testing.T) {
-	intf, err := net.InterfaceByName(*testInterfName)
-	if err != nil {
-		t.Fatal(err)
-	}
-	vip := netip.MustParsePrefix(*testVIPStr)
+	intf := &net.Interface{Index: 7, Name: "enp1s0f1"}
+	vip := netip.MustParsePrefix("192.168.2.248/32")
+	label := "enp1s0f1:0"
+	got := fakeNetlinkRoute(t, nil)
 
-	if err := InterfaceAddPrefix(intf, vip, *testLabel); err != nil {
+	if err := AddAddr(intf, vip, label, nil); err != nil {
 		t.Fatal(err)
 	}
-	if got, err := InterfaceHasPrefix(intf, vip); err != nil {
-		t.Fatal(err)
-	} else if want := true; got != want {
-		t.Errorf("result of InterfaceHasPrefix after AddAddr mimatch, got=%v, want=%v", got, want)
+	if want := netlink.HeaderType(unix.RTM_NEWADDR); got.Header.Type != want {
+		t.Errorf("message type mismatch after AddAddr, got=%d, want=%d", got.Header.Type, want)
 	}
-
-	if err := SendGARP(intf, vip.Addr()); err != nil {
-		t.Fatal(err)
+	if want := netlink.Request | netlink.Acknowledge | netlink.Create | netlink.Excl; got.Header.Flags != want {
+		t.Errorf("flags mismatch after AddAddr, got=%#x, want=%#x", got.Header.Flags, want)
 	}
 
-	if got, err := InterfaceByPrefix(vip); err != nil {
+	if err := DelAddr(intf, vip); err != nil {
 		t.Fatal(err)
-	} else if want := intf; got.Index != want.Index {
-		t.Errorf("index mismatch for InterfaceByPrefix, got=%d, want=%d", got.Index, want.Index)
 	}
-
-	if got, err := InterfaceHasPrefix(intf, vip); err != nil {
-		t.Fatal(err)
-	} else if want := true; got != want {
-		t.Errorf("result mismatch for InterfaceHasPrefix, got=%v, want=%v", got, want)
-	}
-
-	if err := InterfaceDelPrefix(intf, vip); err != nil {
-		t.Fatal(err)
+	if want := netlink.HeaderType(unix.RTM_DELADDR); got.Header.Type != want {
+		t.Errorf("message type mismatch after DelAddr, got=%d, want=%d", got.Header.Type, want)
 	}
-	if got, err := InterfaceHasPrefix(intf, vip); err != nil {
-		t.Fatal(err)
-	} else if want := false; got != want {
-		t.Errorf("result of InterfaceHasPrefix after DelAddr mimatch, got=%v, want=%v", got, want)
+	if want := netlink.Request | netlink.Acknowledge; got.Header.Flags != want {
+		t.Errorf("flags mismatch after DelAddr, got=%#x, want=%#x", got.Header.Flags, want)
 	}
 }